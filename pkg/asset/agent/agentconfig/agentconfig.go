@@ -0,0 +1,85 @@
+package agentconfig
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/installer/pkg/asset"
+)
+
+const agentConfigFilename = "agent-config.yaml"
+
+// OSImage lets a user pin a specific RHCOS image for agent ISO generation, rather than
+// always inheriting the one referenced by the release image.
+type OSImage struct {
+	Version string `json:"version,omitempty"`
+}
+
+// Proxy is a per-InfraEnv proxy override. When set, it takes precedence over the
+// install-config proxy for the InfraEnv(s) generated from this agent-config.
+type Proxy struct {
+	HTTPProxy  string `json:"httpProxy,omitempty"`
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	NoProxy    string `json:"noProxy,omitempty"`
+}
+
+// Config is the user-facing representation of the agent-config.yaml manifest.
+type Config struct {
+	AdditionalNTPSources  []string `json:"additionalNTPSources,omitempty"`
+	AdditionalTrustBundle string   `json:"additionalTrustBundle,omitempty"`
+	OSImage               *OSImage `json:"osImage,omitempty"`
+	Proxy                 *Proxy   `json:"proxy,omitempty"`
+}
+
+// AgentConfig is the asset that loads the user-provided agent-config.yaml manifest.
+type AgentConfig struct {
+	File   *asset.File
+	Config *Config
+}
+
+var _ asset.WritableAsset = (*AgentConfig)(nil)
+
+// Name returns a human friendly name for the asset.
+func (*AgentConfig) Name() string {
+	return "Agent Config"
+}
+
+// Dependencies returns all of the dependencies directly needed to generate the asset.
+func (*AgentConfig) Dependencies() []asset.Asset {
+	return []asset.Asset{}
+}
+
+// Generate is a no-op: agent-config.yaml is always user-provided, never derived.
+func (a *AgentConfig) Generate(_ asset.Parents) error {
+	return nil
+}
+
+// Files returns the files generated by the asset.
+func (a *AgentConfig) Files() []*asset.File {
+	if a.File != nil {
+		return []*asset.File{a.File}
+	}
+	return []*asset.File{}
+}
+
+// Load returns the agent-config asset from disk.
+func (a *AgentConfig) Load(f asset.FileFetcher) (bool, error) {
+	file, err := f.FetchByName(agentConfigFilename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "failed to load %s file", agentConfigFilename)
+	}
+
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(file.Data, config); err != nil {
+		return false, errors.Wrapf(err, "failed to unmarshal %s", agentConfigFilename)
+	}
+
+	a.File, a.Config = file, config
+
+	return true, nil
+}
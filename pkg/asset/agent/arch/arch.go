@@ -0,0 +1,22 @@
+// Package arch normalizes CPU architecture values between their Debian/Go spelling
+// (amd64, arm64, ...) and their RPM spelling (x86_64, aarch64, ...), the form the
+// assisted-service API expects.
+package arch
+
+// rpmArchByGoArch maps each architecture's Debian/Go spelling to its RPM spelling.
+// Architectures not listed here (e.g. ppc64le, s390x) are spelled the same way in both
+// forms and are returned unchanged by Normalize.
+var rpmArchByGoArch = map[string]string{
+	"amd64": "x86_64",
+	"arm64": "aarch64",
+}
+
+// Normalize converts a CPU architecture value, in either its Debian/Go or RPM form,
+// into the canonical RPM form (x86_64, aarch64, ppc64le, s390x, ...) used throughout the
+// agent installer. It is safe to call on a value that is already in RPM form.
+func Normalize(cpuArch string) string {
+	if rpmArch, ok := rpmArchByGoArch[cpuArch]; ok {
+		return rpmArch
+	}
+	return cpuArch
+}
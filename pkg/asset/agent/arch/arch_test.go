@@ -0,0 +1,27 @@
+package arch
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "amd64 to x86_64", input: "amd64", output: "x86_64"},
+		{name: "arm64 to aarch64", input: "arm64", output: "aarch64"},
+		{name: "x86_64 is unchanged", input: "x86_64", output: "x86_64"},
+		{name: "aarch64 is unchanged", input: "aarch64", output: "aarch64"},
+		{name: "ppc64le is unchanged", input: "ppc64le", output: "ppc64le"},
+		{name: "s390x is unchanged", input: "s390x", output: "s390x"},
+		{name: "empty string is unchanged", input: "", output: ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Normalize(tc.input); got != tc.output {
+				t.Errorf("Normalize(%q) = %q, want %q", tc.input, got, tc.output)
+			}
+		})
+	}
+}
@@ -1,13 +1,15 @@
 package manifests
 
 import (
+	"encoding/pem"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 
-	"github.com/coreos/stream-metadata-go/arch"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
@@ -16,17 +18,47 @@ import (
 	"github.com/openshift/installer/pkg/asset"
 	"github.com/openshift/installer/pkg/asset/agent"
 	"github.com/openshift/installer/pkg/asset/agent/agentconfig"
+	"github.com/openshift/installer/pkg/asset/agent/arch"
 	"github.com/openshift/installer/pkg/types"
 )
 
 var (
-	infraEnvFilename = filepath.Join(clusterManifestDir, "infraenv.yaml")
+	infraEnvFilenamePattern = filepath.Join(clusterManifestDir, "infraenv-%s.yaml")
+	infraEnvFilenameGlob    = filepath.Join(clusterManifestDir, "infraenv-*.yaml")
 )
 
-// InfraEnv generates the infraenv.yaml file.
+// infraEnvArchLabel is the label key that distinguishes one per-arch InfraEnv's
+// NMStateConfigLabelSelector from another's. NMStateConfig generation (outside this
+// package) must apply NMStateConfigLabels for the host's own architecture when labeling
+// the NMStateConfig objects it emits; otherwise a host could match every arch's InfraEnv
+// indiscriminately instead of only its own.
+const infraEnvArchLabel = "agent-install.openshift.io/cpu-architecture"
+
+// NMStateConfigLabels returns the label set that binds a host's NMStateConfig to the
+// InfraEnv generated for archName: the shared cluster labels plus, when archName is set,
+// the arch-specific label that keeps per-architecture InfraEnvs from all matching the same
+// hosts. NMStateConfig generation must apply this same function to label its manifests, or
+// no host will ever match a per-arch InfraEnv's selector.
+func NMStateConfigLabels(installConfig *agent.OptionalInstallConfig, archName string) map[string]string {
+	labels := getNMStateConfigLabels(installConfig)
+	if archName == "" {
+		return labels
+	}
+	merged := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		merged[k] = v
+	}
+	merged[infraEnvArchLabel] = archName
+	return merged
+}
+
+// InfraEnv generates the infraenv-<arch>.yaml files, one per CPU architecture present
+// in the cluster (the control plane architecture plus any distinct compute pool
+// architectures), so that day-1 mixed-architecture clusters get a dedicated InfraEnv per
+// architecture.
 type InfraEnv struct {
-	File   *asset.File
-	Config *aiv1beta1.InfraEnv
+	FileList []*asset.File
+	Configs  []*aiv1beta1.InfraEnv
 }
 
 var _ asset.WritableAsset = (*InfraEnv)(nil)
@@ -42,20 +74,44 @@ func (*InfraEnv) Dependencies() []asset.Asset {
 	return []asset.Asset{
 		&agent.OptionalInstallConfig{},
 		&agentconfig.AgentConfig{},
+		&agent.ReleaseImageList{},
+		&agent.ReleaseImageOSVersions{},
 	}
 }
 
-// Generate generates the InfraEnv manifest.
+// Generate generates the InfraEnv manifests, one per CPU architecture in the cluster.
 func (i *InfraEnv) Generate(dependencies asset.Parents) error {
 
 	installConfig := &agent.OptionalInstallConfig{}
 	agentConfig := &agentconfig.AgentConfig{}
-	dependencies.Get(installConfig, agentConfig)
+	releaseImageList := &agent.ReleaseImageList{}
+	releaseImageOSVersions := &agent.ReleaseImageOSVersions{}
+	dependencies.Get(installConfig, agentConfig, releaseImageList, releaseImageOSVersions)
+
+	if installConfig.Config == nil {
+		return i.finish()
+	}
+
+	archs, err := clusterArchitectures(installConfig)
+	if err != nil {
+		return err
+	}
+
+	for _, archName := range archs {
+		if archName != "" {
+			if err := releaseImageList.HasArchitecture(archName); err != nil {
+				return errors.Wrapf(err, "release image does not support architecture %q", archName)
+			}
+		}
+
+		infraEnvName := getInfraEnvName(installConfig)
+		if archName != "" {
+			infraEnvName = fmt.Sprintf("%s-%s", infraEnvName, archName)
+		}
 
-	if installConfig.Config != nil {
 		infraEnv := &aiv1beta1.InfraEnv{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      getInfraEnvName(installConfig),
+				Name:      infraEnvName,
 				Namespace: getObjectMetaNamespace(installConfig),
 			},
 			Spec: aiv1beta1.InfraEnvSpec{
@@ -68,85 +124,252 @@ func (i *InfraEnv) Generate(dependencies asset.Parents) error {
 					Name: getPullSecretName(installConfig),
 				},
 				NMStateConfigLabelSelector: metav1.LabelSelector{
-					MatchLabels: getNMStateConfigLabels(installConfig),
+					MatchLabels: NMStateConfigLabels(installConfig, archName),
 				},
+				CpuArchitecture: archName,
 			},
 		}
 
-		// Use installConfig.Config.ControlPlane.Architecture to determine cpuarchitecture for infraEnv.Spec.CpuArchiteture.
-		// installConfig.Config.ControlPlance.Architecture uses go/Debian cpuarchitecture values (amd64, arm64) so we must convert to rpmArch because infraEnv.Spec.CpuArchitecture expects x86_64 or aarch64.
-		if installConfig.Config.ControlPlane.Architecture != "" {
-			infraEnv.Spec.CpuArchitecture = arch.RpmArch(string(installConfig.Config.ControlPlane.Architecture))
-		}
 		if installConfig.Config.Proxy != nil {
 			infraEnv.Spec.Proxy = getProxy(installConfig)
 		}
 
 		if agentConfig.Config != nil {
 			infraEnv.Spec.AdditionalNTPSources = agentConfig.Config.AdditionalNTPSources
+
+			if agentConfig.Config.OSImage != nil && agentConfig.Config.OSImage.Version != "" {
+				if !releaseImageOSVersions.Contains(agentConfig.Config.OSImage.Version) {
+					return errors.Errorf("agent-config osImage.version %q is not advertised by the release image", agentConfig.Config.OSImage.Version)
+				}
+				infraEnv.Spec.OSImageVersion = agentConfig.Config.OSImage.Version
+			}
+
+			if agentConfig.Config.AdditionalTrustBundle != "" {
+				infraEnv.Spec.AdditionalTrustBundle = agentConfig.Config.AdditionalTrustBundle
+			}
+
+			// A per-InfraEnv proxy override in agent-config takes precedence over the
+			// install-config proxy, since it lets a disconnected/mirrored install target a
+			// proxy that's only reachable from the discovery ISO's network.
+			if agentConfig.Config.Proxy != nil {
+				infraEnv.Spec.Proxy = &aiv1beta1.Proxy{
+					HTTPProxy:  agentConfig.Config.Proxy.HTTPProxy,
+					HTTPSProxy: agentConfig.Config.Proxy.HTTPSProxy,
+					NoProxy:    agentConfig.Config.Proxy.NoProxy,
+				}
+			}
 		}
-		i.Config = infraEnv
+
+		if err := validateInfraEnv(infraEnv); err != nil {
+			return err
+		}
+
+		warnIfNoProxyMissingClusterCIDRs(installConfig, infraEnv.Spec.Proxy)
 
 		infraEnvData, err := yaml.Marshal(infraEnv)
 		if err != nil {
-			return errors.Wrap(err, "failed to marshal agent installer infraEnv")
+			return errors.Wrapf(err, "failed to marshal agent installer infraEnv for architecture %s", archName)
 		}
 
-		i.File = &asset.File{
-			Filename: infraEnvFilename,
+		i.Configs = append(i.Configs, infraEnv)
+		i.FileList = append(i.FileList, &asset.File{
+			Filename: fmt.Sprintf(infraEnvFilenamePattern, archName),
 			Data:     infraEnvData,
+		})
+	}
+
+	return nil
+}
+
+// clusterArchitectures returns the RPM-form, deduplicated list of CPU architectures
+// present in the cluster: the control plane architecture followed by any distinct
+// compute pool architectures, in the order they are declared. If neither the control
+// plane nor any compute pool sets an architecture, it returns a single blank entry so
+// callers still emit one (architecture-agnostic) InfraEnv, matching the pre-multi-arch
+// behavior instead of silently generating nothing.
+func clusterArchitectures(installConfig *agent.OptionalInstallConfig) ([]string, error) {
+	var archs []string
+	seen := map[string]bool{}
+
+	addArch := func(rawArch string) error {
+		if rawArch == "" {
+			return nil
 		}
+		normalized := arch.Normalize(rawArch)
+		if err := validateArchitecture(normalized); err != nil {
+			return err
+		}
+		if !seen[normalized] {
+			seen[normalized] = true
+			archs = append(archs, normalized)
+		}
+		return nil
 	}
 
-	return i.finish()
+	if err := addArch(string(installConfig.Config.ControlPlane.Architecture)); err != nil {
+		return nil, err
+	}
+	for _, pool := range installConfig.Config.Compute {
+		if err := addArch(string(pool.Architecture)); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(archs) == 0 {
+		archs = append(archs, "")
+	}
+
+	return archs, nil
 }
 
 // Files returns the files generated by the asset.
 func (i *InfraEnv) Files() []*asset.File {
-	if i.File != nil {
-		return []*asset.File{i.File}
+	if i.FileList != nil {
+		return i.FileList
 	}
 	return []*asset.File{}
 }
 
-// Load returns infraenv asset from the disk.
+// Load returns the infraenv-<arch>.yaml assets from disk.
 func (i *InfraEnv) Load(f asset.FileFetcher) (bool, error) {
 
-	file, err := f.FetchByName(infraEnvFilename)
+	files, err := f.FetchByPattern(infraEnvFilenameGlob)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return false, nil
 		}
-		return false, errors.Wrap(err, fmt.Sprintf("failed to load %s file", infraEnvFilename))
+		return false, errors.Wrap(err, fmt.Sprintf("failed to load %s files", infraEnvFilenameGlob))
 	}
-
-	config := &aiv1beta1.InfraEnv{}
-	if err := yaml.UnmarshalStrict(file.Data, config); err != nil {
-		return false, errors.Wrapf(err, "failed to unmarshal %s", infraEnvFilename)
+	if len(files) == 0 {
+		return false, nil
 	}
-	// If defined, convert to RpmArch amd64 -> x86_64 or arm64 -> aarch64
-	if config.Spec.CpuArchitecture != "" {
-		config.Spec.CpuArchitecture = arch.RpmArch(config.Spec.CpuArchitecture)
+
+	agentConfig := &agentconfig.AgentConfig{}
+	if _, err := agentConfig.Load(f); err != nil {
+		return false, errors.Wrap(err, "failed to load agent-config.yaml")
 	}
-	i.File, i.Config = file, config
-	if err = i.finish(); err != nil {
-		return false, err
+
+	for _, file := range files {
+		config := &aiv1beta1.InfraEnv{}
+		if err := yaml.UnmarshalStrict(file.Data, config); err != nil {
+			return false, errors.Wrapf(err, "failed to unmarshal %s", file.Filename)
+		}
+		// If defined, normalize amd64 -> x86_64 or arm64 -> aarch64
+		if config.Spec.CpuArchitecture != "" {
+			config.Spec.CpuArchitecture = arch.Normalize(config.Spec.CpuArchitecture)
+		}
+		if err := validateInfraEnv(config); err != nil {
+			return false, err
+		}
+		if err := validateLoadedOSImageVersion(agentConfig, config); err != nil {
+			return false, errors.Wrapf(err, "%s", file.Filename)
+		}
+
+		i.FileList = append(i.FileList, file)
+		i.Configs = append(i.Configs, config)
 	}
 
 	return true, nil
 }
 
-func (i *InfraEnv) finish() error {
+// validateArchitecture rejects any RPM-form architecture the agent installer does not
+// know how to generate an InfraEnv for.
+func validateArchitecture(rpmArch string) error {
+	switch rpmArch {
+	case arch.Normalize(string(types.ArchitectureAMD64)), arch.Normalize(string(types.ArchitectureARM64)), arch.Normalize(string(types.ArchitecturePPC64LE)), arch.Normalize(string(types.ArchitectureS390X)):
+		return nil
+	default:
+		return errors.Errorf("architecture %q is not supported", rpmArch)
+	}
+}
+
+func validateInfraEnv(config *aiv1beta1.InfraEnv) error {
 
-	if i.Config == nil {
+	if config == nil {
 		return errors.New("missing configuration or manifest file")
 	}
 
-	// Throw an error if CpuArchitecture isn't x86_64, aarch64, ppc64le, or ""
-	switch i.Config.Spec.CpuArchitecture {
-	case arch.RpmArch(types.ArchitectureAMD64), arch.RpmArch(types.ArchitectureARM64), arch.RpmArch(types.ArchitecturePPC64LE), "":
-	default:
-		return errors.Errorf("Config.Spec.CpuArchitecture %s is not supported ", i.Config.Spec.CpuArchitecture)
+	// Throw an error if CpuArchitecture isn't x86_64, aarch64, ppc64le, s390x, or ""
+	if config.Spec.CpuArchitecture != "" {
+		if err := validateArchitecture(config.Spec.CpuArchitecture); err != nil {
+			return errors.Wrapf(err, "Config.Spec.CpuArchitecture %s is not supported", config.Spec.CpuArchitecture)
+		}
+	}
+
+	// OSImageVersion is optional, but if it's set (either from agent-config or loaded back
+	// from a previously generated manifest) it must be a well-formed, non-blank version string.
+	if trimmed := strings.TrimSpace(config.Spec.OSImageVersion); trimmed != config.Spec.OSImageVersion || (trimmed == "" && config.Spec.OSImageVersion != "") {
+		return errors.Errorf("Config.Spec.OSImageVersion %q is not a valid OS image version", config.Spec.OSImageVersion)
+	}
+
+	if config.Spec.AdditionalTrustBundle != "" {
+		if block, _ := pem.Decode([]byte(config.Spec.AdditionalTrustBundle)); block == nil || block.Type != "CERTIFICATE" {
+			return errors.New("Config.Spec.AdditionalTrustBundle does not contain a valid PEM-encoded certificate")
+		}
+	}
+
+	if proxy := config.Spec.Proxy; proxy != nil {
+		if proxy.HTTPProxy == "" && proxy.HTTPSProxy == "" {
+			return errors.New("Config.Spec.Proxy must set at least one of HTTPProxy or HTTPSProxy")
+		}
+		for name, rawURL := range map[string]string{"HTTPProxy": proxy.HTTPProxy, "HTTPSProxy": proxy.HTTPSProxy} {
+			if rawURL == "" {
+				continue
+			}
+			if _, err := url.ParseRequestURI(rawURL); err != nil {
+				return errors.Wrapf(err, "Config.Spec.Proxy.%s %q is not a valid URL", name, rawURL)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateLoadedOSImageVersion rejects an InfraEnv manifest loaded from disk whose
+// OSImageVersion conflicts with an osImage.version pinned in agent-config.yaml, since the
+// two are supposed to describe the same RHCOS build and silently preferring one would mask
+// a stale or hand-edited manifest.
+func validateLoadedOSImageVersion(agentConfig *agentconfig.AgentConfig, config *aiv1beta1.InfraEnv) error {
+	if agentConfig.Config == nil || agentConfig.Config.OSImage == nil || agentConfig.Config.OSImage.Version == "" {
+		return nil
+	}
+	if config.Spec.OSImageVersion == "" || config.Spec.OSImageVersion == agentConfig.Config.OSImage.Version {
+		return nil
+	}
+	return errors.Errorf("OSImageVersion %q conflicts with agent-config osImage.version %q", config.Spec.OSImageVersion, agentConfig.Config.OSImage.Version)
+}
+
+// warnIfNoProxyMissingClusterCIDRs logs a warning when a configured proxy's NoProxy list
+// doesn't include the cluster's own network and service CIDRs, since traffic to cluster
+// pods/services would otherwise be routed through the proxy unexpectedly.
+func warnIfNoProxyMissingClusterCIDRs(installConfig *agent.OptionalInstallConfig, proxy *aiv1beta1.Proxy) {
+	if proxy == nil || installConfig.Config == nil {
+		return
+	}
+
+	var cidrs []string
+	for _, net := range installConfig.Config.Networking.ClusterNetwork {
+		cidrs = append(cidrs, net.CIDR.String())
+	}
+	for _, net := range installConfig.Config.Networking.ServiceNetwork {
+		cidrs = append(cidrs, net.String())
+	}
+
+	for _, cidr := range cidrs {
+		if !strings.Contains(proxy.NoProxy, cidr) {
+			logrus.Warnf("Proxy.NoProxy does not include cluster CIDR %s; traffic to it may be routed through the proxy", cidr)
+		}
+	}
+}
+
+func (i *InfraEnv) finish() error {
+	if len(i.Configs) == 0 {
+		return nil
+	}
+	for _, config := range i.Configs {
+		if err := validateInfraEnv(config); err != nil {
+			return err
+		}
 	}
 	return nil
 }
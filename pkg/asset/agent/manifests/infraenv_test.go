@@ -0,0 +1,182 @@
+package manifests
+
+import (
+	"testing"
+
+	aiv1beta1 "github.com/openshift/assisted-service/api/v1beta1"
+
+	"github.com/openshift/installer/pkg/asset/agent"
+	"github.com/openshift/installer/pkg/types"
+)
+
+func TestValidateArchitecture(t *testing.T) {
+	cases := []struct {
+		name    string
+		arch    string
+		wantErr bool
+	}{
+		{name: "x86_64 is supported", arch: "x86_64"},
+		{name: "aarch64 is supported", arch: "aarch64"},
+		{name: "ppc64le is supported", arch: "ppc64le"},
+		{name: "s390x is supported", arch: "s390x"},
+		{name: "unknown architecture is rejected", arch: "riscv64", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateArchitecture(tc.arch)
+			if tc.wantErr && err == nil {
+				t.Fatalf("validateArchitecture(%q) = nil, want error", tc.arch)
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("validateArchitecture(%q) = %v, want nil", tc.arch, err)
+			}
+		})
+	}
+}
+
+func TestValidateInfraEnv(t *testing.T) {
+	cases := []struct {
+		name    string
+		config  *aiv1beta1.InfraEnv
+		wantErr bool
+	}{
+		{
+			name:    "nil config is rejected",
+			config:  nil,
+			wantErr: true,
+		},
+		{
+			name:   "empty config is valid",
+			config: &aiv1beta1.InfraEnv{},
+		},
+		{
+			name: "supported architecture is valid",
+			config: &aiv1beta1.InfraEnv{
+				Spec: aiv1beta1.InfraEnvSpec{CpuArchitecture: "x86_64"},
+			},
+		},
+		{
+			name: "unsupported architecture is rejected",
+			config: &aiv1beta1.InfraEnv{
+				Spec: aiv1beta1.InfraEnvSpec{CpuArchitecture: "riscv64"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "blank OSImageVersion is rejected",
+			config: &aiv1beta1.InfraEnv{
+				Spec: aiv1beta1.InfraEnvSpec{OSImageVersion: "   "},
+			},
+			wantErr: true,
+		},
+		{
+			name: "non-PEM AdditionalTrustBundle is rejected",
+			config: &aiv1beta1.InfraEnv{
+				Spec: aiv1beta1.InfraEnvSpec{AdditionalTrustBundle: "not a certificate"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "proxy with no HTTPProxy or HTTPSProxy is rejected",
+			config: &aiv1beta1.InfraEnv{
+				Spec: aiv1beta1.InfraEnvSpec{Proxy: &aiv1beta1.Proxy{NoProxy: "localhost"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "proxy with an invalid URL is rejected",
+			config: &aiv1beta1.InfraEnv{
+				Spec: aiv1beta1.InfraEnvSpec{Proxy: &aiv1beta1.Proxy{HTTPProxy: "://not-a-url"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid proxy is accepted",
+			config: &aiv1beta1.InfraEnv{
+				Spec: aiv1beta1.InfraEnvSpec{Proxy: &aiv1beta1.Proxy{HTTPProxy: "http://proxy.example.com:8080"}},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateInfraEnv(tc.config)
+			if tc.wantErr && err == nil {
+				t.Fatalf("validateInfraEnv() = nil, want error")
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("validateInfraEnv() = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestClusterArchitectures(t *testing.T) {
+	cases := []struct {
+		name         string
+		controlPlane types.Architecture
+		compute      []types.Architecture
+		want         []string
+		wantErr      bool
+	}{
+		{
+			name:         "single control-plane architecture",
+			controlPlane: types.ArchitectureAMD64,
+			want:         []string{"x86_64"},
+		},
+		{
+			name:         "distinct control-plane and compute architectures",
+			controlPlane: types.ArchitectureAMD64,
+			compute:      []types.Architecture{types.ArchitectureARM64},
+			want:         []string{"x86_64", "aarch64"},
+		},
+		{
+			name:         "duplicate compute architecture is deduplicated",
+			controlPlane: types.ArchitectureAMD64,
+			compute:      []types.Architecture{types.ArchitectureAMD64, types.ArchitectureARM64},
+			want:         []string{"x86_64", "aarch64"},
+		},
+		{
+			name:         "unsupported architecture is rejected",
+			controlPlane: types.Architecture("riscv64"),
+			wantErr:      true,
+		},
+		{
+			name: "blank control-plane architecture with no compute pools returns a single blank entry",
+			want: []string{""},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			installConfig := &agent.OptionalInstallConfig{
+				Config: &types.InstallConfig{
+					ControlPlane: &types.MachinePool{Architecture: tc.controlPlane},
+				},
+			}
+			for _, computeArch := range tc.compute {
+				installConfig.Config.Compute = append(installConfig.Config.Compute, types.MachinePool{Architecture: computeArch})
+			}
+
+			got, err := clusterArchitectures(installConfig)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("clusterArchitectures() = %v, nil, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("clusterArchitectures() = nil, %v, want no error", err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("clusterArchitectures() = %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("clusterArchitectures() = %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/openshift/installer/pkg/asset"
+	"github.com/openshift/installer/pkg/asset/agent/arch"
+	"github.com/openshift/installer/pkg/asset/releaseimage"
+)
+
+// releaseArchitectureAnnotation is the manifest annotation `oc adm release info` exposes
+// to identify the CPU architecture(s) a release image was built for. Its value is "multi"
+// for a multi-arch release payload.
+const releaseArchitectureAnnotation = "release.openshift.io/architecture"
+
+// multiArchPayload is the value of releaseArchitectureAnnotation on a multi-arch release image.
+const multiArchPayload = "multi"
+
+// ReleaseImageArch determines the RPM-form CPU architecture of the release image
+// referenced by the install config, so it can be validated against the agent
+// installer's configured architecture before ISO generation.
+type ReleaseImageArch struct {
+	Arch string
+}
+
+var _ asset.Asset = (*ReleaseImageArch)(nil)
+
+// Name returns a human friendly name for the asset.
+func (*ReleaseImageArch) Name() string {
+	return "Release Image Architecture"
+}
+
+// Dependencies returns all of the dependencies directly needed to generate the asset.
+func (*ReleaseImageArch) Dependencies() []asset.Asset {
+	return []asset.Asset{
+		&OptionalInstallConfig{},
+	}
+}
+
+// Generate determines the release image CPU architecture by invoking `oc adm release
+// info` against the pull secret referenced by the install config. For a multi-arch
+// release image, the `release.openshift.io/architecture` annotation is re-queried with
+// `--filter-by-os` for the architecture the install config requests.
+func (a *ReleaseImageArch) Generate(dependencies asset.Parents) error {
+	installConfig := &OptionalInstallConfig{}
+	dependencies.Get(installConfig)
+
+	if installConfig.Config == nil {
+		return nil
+	}
+
+	pullSecret := installConfig.Config.PullSecret
+	releaseImagePullSpec := releaseimage.Default()
+
+	releaseArch, err := releaseImageArchitecture(releaseImagePullSpec, pullSecret, "")
+	if err != nil {
+		return errors.Wrap(err, "failed to determine release image architecture")
+	}
+
+	if releaseArch == multiArchPayload {
+		targetArch := arch.Normalize(string(installConfig.Config.ControlPlane.Architecture))
+		releaseArch, err = releaseImageArchitecture(releaseImagePullSpec, pullSecret, targetArch)
+		if err != nil {
+			return errors.Wrapf(err, "failed to determine release image architecture for %s", targetArch)
+		}
+	}
+
+	a.Arch = arch.Normalize(releaseArch)
+
+	return nil
+}
+
+// releaseImageArchitecture shells out to `oc adm release info` to read the
+// releaseArchitectureAnnotation for the given release image. When filterArch is
+// non-empty, the child manifest matching that RPM-form architecture is queried instead
+// of the top-level (possibly multi-arch) manifest.
+func releaseImageArchitecture(pullSpec, pullSecret, filterArch string) (string, error) {
+	args := []string{
+		"adm", "release", "info",
+		"-o", "go-template={{index .metadata.annotations \"" + releaseArchitectureAnnotation + "\"}}",
+		"--registry-config=-",
+	}
+	if filterArch != "" {
+		args = append(args, "--filter-by-os=linux/"+goArch(filterArch))
+	}
+	args = append(args, pullSpec)
+
+	cmd := exec.Command("oc", args...)
+	cmd.Stdin = strings.NewReader(pullSecret)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", errors.Wrapf(err, "oc adm release info failed: %s", stderr.String())
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// goArch converts an RPM-form CPU architecture back to the Debian/Go form
+// `--filter-by-os` expects.
+func goArch(rpmArch string) string {
+	switch rpmArch {
+	case "x86_64":
+		return "amd64"
+	case "aarch64":
+		return "arm64"
+	default:
+		return rpmArch
+	}
+}
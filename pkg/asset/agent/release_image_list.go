@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/openshift/installer/pkg/asset"
+	"github.com/openshift/installer/pkg/asset/releaseimage"
+)
+
+// ReleaseImageList answers whether a (possibly multi-arch) release image supports a given
+// CPU architecture, so that requests for heterogeneous-architecture clusters can be
+// validated against what the release payload actually ships. It caches only the single
+// architecture ReleaseImageArch already confirmed as a fast path; any other architecture
+// is confirmed by querying the release payload directly, since a multi-arch payload's full
+// set of supported architectures isn't enumerated anywhere in its top-level manifest.
+type ReleaseImageList struct {
+	confirmedArch string
+
+	pullSpec   string
+	pullSecret string
+}
+
+var _ asset.Asset = (*ReleaseImageList)(nil)
+
+// Name returns a human friendly name for the asset.
+func (*ReleaseImageList) Name() string {
+	return "Release Image Architecture List"
+}
+
+// Dependencies returns all of the dependencies directly needed to generate the asset.
+func (*ReleaseImageList) Dependencies() []asset.Asset {
+	return []asset.Asset{
+		&OptionalInstallConfig{},
+		&ReleaseImageArch{},
+	}
+}
+
+// Generate records the top-level release image architecture ReleaseImageArch already
+// determined, so HasArchitecture can confirm it without a redundant `oc adm release info`
+// call. For a multi-arch payload that is the architecture of the child manifest matching
+// the control plane; it is not cached when ReleaseImageArch reports the literal value
+// "multi" (a multi-arch payload with no control plane architecture set), since that is
+// not a real CPU architecture to compare against.
+func (l *ReleaseImageList) Generate(dependencies asset.Parents) error {
+	installConfig := &OptionalInstallConfig{}
+	releaseImageArch := &ReleaseImageArch{}
+	dependencies.Get(installConfig, releaseImageArch)
+
+	if installConfig.Config == nil {
+		return nil
+	}
+
+	l.pullSpec = releaseimage.Default()
+	l.pullSecret = installConfig.Config.PullSecret
+
+	if releaseImageArch.Arch != multiArchPayload {
+		l.confirmedArch = releaseImageArch.Arch
+	}
+
+	return nil
+}
+
+// HasArchitecture reports whether the release image supports the given RPM-form CPU
+// architecture. It's a cache hit only when rpmArch is the single architecture
+// ReleaseImageArch already confirmed (for a multi-arch payload, the control plane
+// architecture); any other architecture is confirmed by querying the matching child
+// manifest directly.
+func (l *ReleaseImageList) HasArchitecture(rpmArch string) error {
+	if l.confirmedArch != "" && l.confirmedArch == rpmArch {
+		return nil
+	}
+
+	if l.pullSpec == "" {
+		return errors.Errorf("architecture %s is not present in the release image", rpmArch)
+	}
+
+	if _, err := releaseImageArchitecture(l.pullSpec, l.pullSecret, rpmArch); err != nil {
+		return errors.Wrapf(err, "architecture %s is not present in the release image", rpmArch)
+	}
+
+	return nil
+}
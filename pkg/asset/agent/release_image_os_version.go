@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"os/exec"
+	"strings"
+
+	"github.com/coreos/stream-metadata-go/stream"
+	"github.com/pkg/errors"
+
+	"github.com/openshift/installer/pkg/asset"
+	"github.com/openshift/installer/pkg/asset/agent/agentconfig"
+	"github.com/openshift/installer/pkg/asset/releaseimage"
+)
+
+// coreOSStreamMetadataPath is the path, inside the release image, to the CoreOS stream
+// metadata document that advertises every RHCOS build version the release was published
+// with.
+const coreOSStreamMetadataPath = "coreos/coreos-stream.json"
+
+// ReleaseImageOSVersions lists every RHCOS build version advertised by the release
+// image's embedded CoreOS stream metadata, so that an agent-config osImage.version
+// override can be validated against what the release image actually ships.
+type ReleaseImageOSVersions struct {
+	Versions []string
+}
+
+var _ asset.Asset = (*ReleaseImageOSVersions)(nil)
+
+// Name returns a human friendly name for the asset.
+func (*ReleaseImageOSVersions) Name() string {
+	return "Release Image OS Versions"
+}
+
+// Dependencies returns all of the dependencies directly needed to generate the asset.
+func (*ReleaseImageOSVersions) Dependencies() []asset.Asset {
+	return []asset.Asset{
+		&OptionalInstallConfig{},
+		&agentconfig.AgentConfig{},
+	}
+}
+
+// Generate extracts the CoreOS stream metadata embedded in the release image and
+// records every RHCOS build version it advertises. Extraction is skipped unless
+// agent-config.yaml pins an osImage.version, since that's the only thing this data is
+// used to validate and the extraction requires an extra registry round-trip.
+func (r *ReleaseImageOSVersions) Generate(dependencies asset.Parents) error {
+	installConfig := &OptionalInstallConfig{}
+	agentConfig := &agentconfig.AgentConfig{}
+	dependencies.Get(installConfig, agentConfig)
+
+	if installConfig.Config == nil {
+		return nil
+	}
+
+	if agentConfig.Config == nil || agentConfig.Config.OSImage == nil || agentConfig.Config.OSImage.Version == "" {
+		return nil
+	}
+
+	data, err := extractCoreOSStreamMetadata(releaseimage.Default(), installConfig.Config.PullSecret)
+	if err != nil {
+		return errors.Wrap(err, "failed to extract CoreOS stream metadata from release image")
+	}
+
+	var streamDoc stream.Stream
+	if err := json.Unmarshal(data, &streamDoc); err != nil {
+		return errors.Wrap(err, "failed to parse CoreOS stream metadata")
+	}
+
+	for _, arch := range streamDoc.Architectures {
+		for _, artifact := range arch.Artifacts {
+			if artifact.Release != "" {
+				r.Versions = append(r.Versions, artifact.Release)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Contains reports whether version matches one of the RHCOS build versions the release
+// image advertises.
+func (r *ReleaseImageOSVersions) Contains(version string) bool {
+	for _, v := range r.Versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+func extractCoreOSStreamMetadata(pullSpec, pullSecret string) ([]byte, error) {
+	cmd := exec.Command("oc", "adm", "release", "extract",
+		"--from="+pullSpec,
+		"--file="+coreOSStreamMetadataPath,
+		"--registry-config=-",
+	)
+	cmd.Stdin = strings.NewReader(pullSecret)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Wrapf(err, "oc adm release extract failed: %s", stderr.String())
+	}
+
+	return stdout.Bytes(), nil
+}